@@ -8,19 +8,22 @@ import (
 	"flag"
 	"fmt"
 	"log"
+	"net/http"
 	"os"
 	"os/exec"
+	"os/signal"
 	"path/filepath"
 	"regexp"
 	"strings"
+	"sync"
 	"syscall"
+	"time"
 
-	"github.com/howeyc/fsnotify"
+	"github.com/tadvi/reload/internal/glob"
+	"github.com/tadvi/reload/internal/livereload"
+	"github.com/tadvi/reload/internal/watch"
 )
 
-// Milliseconds to wait for the next job to begin after a file change
-const WorkDelay = 500
-
 // Default pattern to match files which trigger a reload
 const FilePattern = `.+\.tpl|.+\.htm|.+\.html|.+\.js|.+\.css|.+\.yml|.+\.yaml|.+\.exe`
 
@@ -46,21 +49,119 @@ func (g *globList) Matches(value string) bool {
 
 var (
 	flag_pattern   = flag.String("pattern", FilePattern, "Watch all dirs. recursively")
-	flag_directory = flag.String("dir", ".", "Directory to watch for changes")
 	flag_recursive = flag.Bool("recursive", true, "Watch all dirs. recursively")
 
 	// initialized in main() due to custom type.
 	flag_excludedDirs  globList
 	flag_excludedFiles globList
 	flag_includedFiles globList
+
+	// flag_dirs holds one or more -dir entries. Each entry is either a
+	// plain directory ("./backend") or a doublestar glob such as
+	// "src/**/*.go" or an exclusion such as "!vendor/**".
+	flag_dirs globList
+
+	flag_killSignal  = flag.String("kill-signal", "SIGTERM", "Signal sent to the running command before it is force-killed (SIGINT, SIGTERM or SIGHUP)")
+	flag_killTimeout = flag.Duration("kill-timeout", 5*time.Second, "How long to wait for the command to exit after kill-signal before forcing a Kill()")
+
+	flag_serveReload = flag.String("serve-reload", "", "Address to serve a livereload websocket/SSE endpoint on (e.g. :35729); disabled when empty")
+
+	flag_config = flag.String("config", "", "Path to a reload.yaml declaring multiple named projects to watch and build; replaces the single-command CLI mode")
+
+	flag_debounce = flag.Duration("debounce", 500*time.Millisecond, "Coalesce bursts of file events within this window into a single restart")
+
+	flag_watcher      = flag.String("watcher", "fsnotify", "File-watching backend: fsnotify or poll. fsnotify auto-falls-back to poll if it can't be created")
+	flag_pollInterval = flag.Duration("poll-interval", time.Second, "Polling interval used by -watcher=poll")
 )
 
+// debouncer coalesces a burst of calls to trigger into a single call to fn,
+// fired window after the last trigger. This absorbs editors like vim or
+// IntelliJ that emit several write events per save.
+type debouncer struct {
+	window time.Duration
+
+	mu    sync.Mutex
+	timer *time.Timer
+}
+
+func newDebouncer(window time.Duration) *debouncer {
+	return &debouncer{window: window}
+}
+
+func (d *debouncer) trigger(fn func()) {
+	d.mu.Lock()
+	defer d.mu.Unlock()
+
+	if d.timer != nil {
+		d.timer.Stop()
+	}
+	d.timer = time.AfterFunc(d.window, fn)
+}
+
 var logger *log.Logger
 
+// currentProcess is guarded by currentProcessMu so the signal handler in
+// main() can forward a stop signal to whatever the runner is currently running.
+var (
+	currentProcessMu sync.Mutex
+	currentProcess   *os.Process
+)
+
 func matchesPattern(pattern *regexp.Regexp, file string) bool {
 	return pattern.MatchString(file)
 }
 
+// watchRoots turns a set of -dir entries (plain directories or doublestar
+// globs) into the unique set of directories filepath.Walk should start
+// from.
+func watchRoots(dirs []string) []string {
+	seen := map[string]bool{}
+	var roots []string
+
+	for _, d := range dirs {
+		root := glob.Root(d)
+		if root == "" || seen[root] {
+			continue
+		}
+		seen[root] = true
+		roots = append(roots, root)
+	}
+
+	return roots
+}
+
+// addWatchTree walks root and adds every subdirectory to watcher, skipping
+// directories matched by flag_excludedDirs or dirGlob's exclusions. It is
+// also used to pick up directories created while reload is already running.
+func addWatchTree(watcher watch.Watcher, root string, dirGlob *glob.Set) error {
+	return filepath.Walk(root, func(path string, info os.FileInfo, err error) error {
+		if err != nil || !info.IsDir() {
+			return err
+		}
+		if flag_excludedDirs.Matches(info.Name()) || dirGlob.Excludes(path) {
+			return filepath.SkipDir
+		}
+		return watcher.Add(path)
+	})
+}
+
+// parseSignal maps a signal name from -kill-signal to a syscall.Signal.
+func parseSignal(name string) syscall.Signal {
+	switch strings.ToUpper(name) {
+	case "SIGINT", "INT":
+		return syscall.SIGINT
+	case "SIGTERM", "TERM":
+		return syscall.SIGTERM
+	case "SIGHUP", "HUP":
+		return syscall.SIGHUP
+	case "SIGKILL", "KILL":
+		return syscall.SIGKILL
+	default:
+		logger.Fatalf("Unknown -kill-signal %q", name)
+	}
+	return syscall.SIGTERM
+}
+
 // Start the supplied command and return stdout and stderr pipes for loging.
 func startCommand(command string) (cmd *exec.Cmd, err error) {
 	args := strings.Split(command, " ")
@@ -87,31 +188,71 @@ func startCommand(command string) (cmd *exec.Cmd, err error) {
 }
 
 // Run the command in the given string and restart it after
-// a message was received on the buildDone channel.
-func runner(command string, startRun <-chan struct{}) {
-	var currentProcess *os.Process
+// a message was received on the startRun channel. Before starting a new
+// run it asks the previous process to stop gracefully via killSignal,
+// giving it up to killTimeout to exit before escalating to Kill().
+//
+// currentProcessMu is held for the whole stop-then-start sequence, not
+// just while reading/writing currentProcess: releasing it in between lets
+// the SIGINT/SIGTERM handler in main() grab the same *os.Process and call
+// stopProcess on it concurrently, which can Signal/Kill an already-reaped
+// process and hit the "Could not kill child process" Fatal.
+func runner(command string, startRun <-chan struct{}, killSignal syscall.Signal, killTimeout time.Duration) {
 	for {
 		<-startRun
 
+		currentProcessMu.Lock()
+
 		if currentProcess != nil {
-			killProcess(currentProcess)
+			stopProcess(currentProcess, killSignal, killTimeout)
+			currentProcess = nil
 		}
 
 		cmd, err := startCommand(command)
 		if err != nil {
+			currentProcessMu.Unlock()
 			logger.Fatal("Could not start command")
 		}
 
 		currentProcess = cmd.Process
+		currentProcessMu.Unlock()
 	}
 }
 
-func killProcess(process *os.Process) {
+// stopProcess sends sig to process and waits up to timeout for it to exit on
+// its own before escalating to Kill(). This gives servers and database
+// clients a chance to flush state instead of being killed outright.
+func stopProcess(process *os.Process, sig syscall.Signal, timeout time.Duration) {
+	if process == nil {
+		return
+	}
+
+	// A single goroutine owns the Wait() call for the lifetime of this
+	// function: calling Wait() twice on the same *os.Process races, and
+	// the loser gets "wait: no child processes" instead of the real result.
+	done := make(chan error, 1)
+	go func() {
+		_, err := process.Wait()
+		done <- err
+	}()
+
+	if err := process.Signal(sig); err != nil {
+		logger.Println("Could not send", sig, "to child process:", err)
+	} else {
+		select {
+		case <-done:
+			logger.Println("Reloaded")
+			return
+		case <-time.After(timeout):
+			logger.Println("Child process did not exit within", timeout, "- killing")
+		}
+	}
+
 	if err := process.Kill(); err != nil {
 		logger.Fatal("Could not kill child process. Aborting due to danger of infinite forks.")
 	}
 
-	if _, err := process.Wait(); err != nil {
+	if err := <-done; err != nil {
 		logger.Fatal("Could not wait for child process. Aborting due to danger of infinite forks.")
 	}
 
@@ -122,46 +263,42 @@ func main() {
 	flag.Var(&flag_excludedDirs, "exclude-dir", " Don't watch directories matching this name")
 	flag.Var(&flag_excludedFiles, "exclude", " Don't watch files matching this name")
 	flag.Var(&flag_includedFiles, "include", " Watch files matching this name")
+	flag.Var(&flag_dirs, "dir", "Directory to watch for changes; repeatable, accepts doublestar globs (src/**/*.go) and \"!\"-prefixed exclusions (!vendor/**)")
 
 	flag.Parse()
 	logger = log.New(os.Stdout, "", log.LstdFlags)
 
+	if *flag_config != "" {
+		runConfigMode(*flag_config)
+		return
+	}
+
 	if flag.NArg() < 1 {
 		fmt.Fprintf(os.Stderr, "program is required as last parameter.\n")
 		os.Exit(1)
 	}
 	flag_command := flag.Arg(0)
 
-	if *flag_directory == "" {
-		fmt.Fprintf(os.Stderr, "-dir=... is required.\n")
-		os.Exit(1)
+	if len(flag_dirs) == 0 {
+		flag_dirs = globList{"."}
 	}
+	dirGlob := glob.New(flag_dirs)
 
-	watcher, err := fsnotify.NewWatcher()
+	watcher, err := watch.New(*flag_watcher, *flag_pollInterval)
 	if err != nil {
 		logger.Fatal(err)
 	}
 	defer watcher.Close()
 
-	if *flag_recursive == true {
-		err = filepath.Walk(*flag_directory, func(path string, info os.FileInfo, err error) error {
-			if err == nil && info.IsDir() {
-				if flag_excludedDirs.Matches(info.Name()) {
-					return filepath.SkipDir
-				} else {
-					return watcher.Watch(path)
-				}
+	for _, root := range watchRoots(flag_dirs) {
+		if *flag_recursive == true {
+			if err := addWatchTree(watcher, root, dirGlob); err != nil {
+				logger.Fatal("filepath.Walk():", err)
+			}
+		} else {
+			if err := watcher.Add(root); err != nil {
+				logger.Fatal("watcher.Add():", err)
 			}
-			return err
-		})
-
-		if err != nil {
-			logger.Fatal("filepath.Walk():", err)
-		}
-
-	} else {
-		if err := watcher.Watch(*flag_directory); err != nil {
-			logger.Fatal("watcher.Watch():", err)
 		}
 	}
 
@@ -171,31 +308,109 @@ func main() {
 	logger.Println("Full pattern:", fullPattern)
 
 	pattern := regexp.MustCompile(fullPattern)
+	killSignal := parseSignal(*flag_killSignal)
+
+	var broadcaster *livereload.Broadcaster
+	if *flag_serveReload != "" {
+		broadcaster = livereload.NewBroadcaster()
+		go func() {
+			if err := http.ListenAndServe(*flag_serveReload, broadcaster.Handler()); err != nil {
+				logger.Fatal("livereload: ", err)
+			}
+		}()
+		logger.Println("Serving livereload on", *flag_serveReload)
+	}
+
 	startRun := make(chan struct{}, 20)
 	startRun <- struct{}{}
-	go runner(flag_command, startRun)
+	go runner(flag_command, startRun, killSignal, *flag_killTimeout)
+
+	debounce := newDebouncer(*flag_debounce)
+	var (
+		pendingMu    sync.Mutex
+		pendingPaths = map[string]string{}
+	)
+
+	sigCh := make(chan os.Signal, 1)
+	signal.Notify(sigCh, syscall.SIGINT, syscall.SIGTERM)
+	go func() {
+		sig := <-sigCh
+		logger.Println("Received", sig, "- stopping child process")
+
+		// Held for the whole stopProcess call, not just the pointer read,
+		// so this can't race runner()'s own stop-then-restart sequence.
+		currentProcessMu.Lock()
+		stopProcess(currentProcess, killSignal, *flag_killTimeout)
+		currentProcessMu.Unlock()
+
+		os.Exit(0)
+	}()
 
 	for {
 		select {
-		case ev := <-watcher.Event:
-			if ev.Name != "" {
-				base := filepath.Base(ev.Name)
+		case ev := <-watcher.Events():
+			if ev.Name == "" || ev.Op == watch.Chmod {
+				continue
+			}
+			if dirGlob.Excludes(ev.Name) {
+				continue
+			}
 
-				if flag_includedFiles.Matches(base) || matchesPattern(pattern, ev.Name) {
-					if !flag_excludedFiles.Matches(base) {
-						startRun <- struct{}{}
+			if ev.Has(watch.Create) && *flag_recursive {
+				if info, err := os.Stat(ev.Name); err == nil && info.IsDir() {
+					if err := addWatchTree(watcher, ev.Name, dirGlob); err != nil {
+						logger.Println("filepath.Walk():", ev.Name, err)
 					}
+					continue
 				}
 			}
 
-		case err := <-watcher.Error:
-			if v, ok := err.(*os.SyscallError); ok {
-				if v.Err == syscall.EINTR {
-					continue
-				}
-				logger.Fatal("watcher.Error: SyscallError:", v)
+			if ev.Has(watch.Remove) || ev.Has(watch.Rename) {
+				watcher.Remove(ev.Name)
+			}
+
+			base := filepath.Base(ev.Name)
+			if !dirGlob.Includes(ev.Name) {
+				continue
+			}
+			if !flag_includedFiles.Matches(base) && !matchesPattern(pattern, ev.Name) {
+				continue
+			}
+			if flag_excludedFiles.Matches(base) {
+				continue
 			}
-			logger.Fatal("watcher.Error:", err)
+
+			pendingMu.Lock()
+			pendingPaths[ev.Name] = filepath.Ext(ev.Name)
+			pendingMu.Unlock()
+
+			debounce.trigger(func() {
+				startRun <- struct{}{}
+
+				if broadcaster == nil {
+					return
+				}
+
+				pendingMu.Lock()
+				paths := pendingPaths
+				pendingPaths = map[string]string{}
+				pendingMu.Unlock()
+
+				msgType := "css"
+				for _, ext := range paths {
+					if ext != ".css" {
+						msgType = "reload"
+						break
+					}
+				}
+
+				for path, ext := range paths {
+					broadcaster.Broadcast(livereload.Message{Type: msgType, Path: path, Ext: ext})
+				}
+			})
+
+		case err := <-watcher.Errors():
+			logger.Println("watcher.Errors():", err)
 		}
 	}
 }
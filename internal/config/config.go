@@ -0,0 +1,70 @@
+/*
+Package config parses the reload.yaml project configuration used by
+--config mode, where a single reload invocation watches and builds several
+named projects (e.g. a "frontend" and a "backend" in one monorepo) instead
+of just one command.
+*/
+package config
+
+import (
+	"fmt"
+	"io/ioutil"
+
+	yaml "gopkg.in/yaml.v2"
+)
+
+// Script is one step of a project's build pipeline. Type controls when it
+// runs: "before" once before the project handles its first change, "change" on
+// every qualifying file change, and "after" once on shutdown.
+type Script struct {
+	Type    string `yaml:"type"`
+	Command string `yaml:"command"`
+}
+
+// Project describes one independently watched and built part of a
+// monorepo, such as "./frontend" or "./backend".
+type Project struct {
+	Path         string            `yaml:"path"`
+	Extensions   []string          `yaml:"extensions"`
+	IgnoredPaths []string          `yaml:"ignored_paths"`
+	Env          map[string]string `yaml:"env"`
+	Args         []string          `yaml:"args"`
+	Scripts      []Script          `yaml:"scripts"`
+}
+
+// Config is the top-level shape of a reload.yaml file: a set of named
+// projects, each with its own watch path and build pipeline.
+type Config struct {
+	Projects map[string]Project `yaml:"projects"`
+}
+
+// Load reads and parses a reload.yaml config file from path.
+func Load(path string) (*Config, error) {
+	data, err := ioutil.ReadFile(path)
+	if err != nil {
+		return nil, fmt.Errorf("can't read config %s: %s", path, err)
+	}
+
+	var cfg Config
+	if err := yaml.Unmarshal(data, &cfg); err != nil {
+		return nil, fmt.Errorf("can't parse config %s: %s", path, err)
+	}
+
+	if len(cfg.Projects) == 0 {
+		return nil, fmt.Errorf("%s declares no projects", path)
+	}
+
+	for name, proj := range cfg.Projects {
+		changes := 0
+		for _, s := range proj.Scripts {
+			if s.Type == "change" {
+				changes++
+			}
+		}
+		if changes > 1 {
+			return nil, fmt.Errorf("project %s declares %d \"change\" scripts, only one is supported", name, changes)
+		}
+	}
+
+	return &cfg, nil
+}
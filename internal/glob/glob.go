@@ -0,0 +1,96 @@
+// Package glob matches paths against doublestar-style glob patterns, e.g.
+// a double-star wildcard path or a "!"-prefixed exclusion, used by --dir to
+// watch several trees and skip the parts of them reload shouldn't follow.
+package glob
+
+import (
+	"path/filepath"
+	"strings"
+
+	"github.com/bmatcuk/doublestar"
+)
+
+// Set is an ordered list of doublestar patterns. A pattern prefixed with
+// "!" is an exclusion; every other pattern is an inclusion.
+type Set struct {
+	includes []string
+	excludes []string
+}
+
+// New builds a Set from patterns such as "src/**/*.go" or "!vendor/**".
+func New(patterns []string) *Set {
+	s := &Set{}
+	for _, p := range patterns {
+		if strings.HasPrefix(p, "!") {
+			s.excludes = append(s.excludes, strings.TrimPrefix(p, "!"))
+		} else {
+			s.includes = append(s.includes, p)
+		}
+	}
+	return s
+}
+
+// Includes reports whether path matches one of the set's inclusion
+// patterns. A Set with no inclusion patterns includes everything.
+func (s *Set) Includes(path string) bool {
+	if len(s.includes) == 0 {
+		return true
+	}
+	for _, p := range s.includes {
+		if matches(p, path) {
+			return true
+		}
+	}
+	return false
+}
+
+// Excludes reports whether path matches one of the set's "!"-prefixed
+// exclusion patterns.
+func (s *Set) Excludes(path string) bool {
+	for _, p := range s.excludes {
+		if matches(p, path) {
+			return true
+		}
+	}
+	return false
+}
+
+// matches reports whether path is matched by pattern. A pattern with no
+// wildcard characters is a plain directory (e.g. "." or "./backend"), and
+// matches path itself plus everything under it; doublestar.Match would
+// otherwise require an exact match and never match any file inside it.
+// Patterns with wildcards are matched with doublestar as-is.
+func matches(pattern, path string) bool {
+	if !strings.ContainsAny(pattern, "*?[") {
+		pattern = filepath.Clean(pattern)
+		path = filepath.Clean(path)
+
+		if pattern == "." {
+			return !strings.HasPrefix(path, "..")
+		}
+		return path == pattern || strings.HasPrefix(path, pattern+string(filepath.Separator))
+	}
+
+	ok, _ := doublestar.Match(pattern, path)
+	return ok
+}
+
+// Root returns the directory part of pattern before its first wildcard
+// character, a suitable filepath.Walk starting point. A pattern with no
+// wildcards is returned unchanged. A "!"-prefixed exclusion pattern
+// contributes no root of its own.
+func Root(pattern string) string {
+	if strings.HasPrefix(pattern, "!") {
+		return ""
+	}
+
+	if i := strings.IndexAny(pattern, "*?["); i >= 0 {
+		root := filepath.Dir(pattern[:i])
+		if root == "" {
+			root = "."
+		}
+		return root
+	}
+
+	return pattern
+}
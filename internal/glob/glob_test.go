@@ -0,0 +1,66 @@
+package glob
+
+import "testing"
+
+func TestSetIncludes(t *testing.T) {
+	tests := []struct {
+		patterns []string
+		path     string
+		want     bool
+	}{
+		{[]string{"."}, "main.go", true},
+		{[]string{"."}, "sub/main.go", true},
+		{[]string{".."}, "main.go", false},
+		{[]string{"./backend"}, "backend/main.go", true},
+		{[]string{"./backend"}, "frontend/main.go", false},
+		{[]string{"src/**/*.go"}, "src/a/b/main.go", true},
+		{[]string{"src/**/*.go"}, "src/main.js", false},
+		{nil, "anything", true},
+	}
+
+	for _, tt := range tests {
+		s := New(tt.patterns)
+		if got := s.Includes(tt.path); got != tt.want {
+			t.Errorf("New(%v).Includes(%q) = %v, want %v", tt.patterns, tt.path, got, tt.want)
+		}
+	}
+}
+
+func TestSetExcludes(t *testing.T) {
+	tests := []struct {
+		patterns []string
+		path     string
+		want     bool
+	}{
+		{[]string{"!vendor"}, "vendor/pkg/main.go", true},
+		{[]string{"!vendor"}, "backend/main.go", false},
+		{[]string{"!vendor/**"}, "vendor/pkg/main.go", true},
+		{[]string{"src/**/*.go", "!vendor/**"}, "vendor/pkg/main.go", true},
+	}
+
+	for _, tt := range tests {
+		s := New(tt.patterns)
+		if got := s.Excludes(tt.path); got != tt.want {
+			t.Errorf("New(%v).Excludes(%q) = %v, want %v", tt.patterns, tt.path, got, tt.want)
+		}
+	}
+}
+
+func TestRoot(t *testing.T) {
+	tests := []struct {
+		pattern string
+		want    string
+	}{
+		{".", "."},
+		{"./backend", "./backend"},
+		{"src/**/*.go", "src"},
+		{"*.go", "."},
+		{"!vendor/**", ""},
+	}
+
+	for _, tt := range tests {
+		if got := Root(tt.pattern); got != tt.want {
+			t.Errorf("Root(%q) = %q, want %q", tt.pattern, got, tt.want)
+		}
+	}
+}
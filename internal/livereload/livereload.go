@@ -0,0 +1,160 @@
+/*
+Package livereload implements a minimal browser-refresh subsystem for
+reload. It exposes a WebSocket endpoint, a Server-Sent Events endpoint,
+and a small injectable JS snippet, and broadcasts a JSON message to every
+connected client whenever a watched file change triggers a rebuild.
+*/
+package livereload
+
+import (
+	"container/list"
+	"encoding/json"
+	"fmt"
+	"io"
+	"net/http"
+	"sync"
+
+	"golang.org/x/net/websocket"
+)
+
+// Message is broadcast to every connected browser client when a watched
+// file change fires startRun. CSS-only changes use Type "css" so the
+// client can hot-swap stylesheets instead of doing a full page reload.
+type Message struct {
+	Type string `json:"type"`
+	Path string `json:"path,omitempty"`
+	Ext  string `json:"ext,omitempty"`
+}
+
+// client is a single connected browser, reached over either WebSocket or SSE.
+type client struct {
+	send chan []byte
+}
+
+// Broadcaster fans out reload Messages to every connected browser client.
+// Dead connections are pruned the next time a write to them fails.
+type Broadcaster struct {
+	mu    sync.Mutex
+	conns *list.List
+}
+
+// NewBroadcaster returns an empty Broadcaster ready to accept connections.
+func NewBroadcaster() *Broadcaster {
+	return &Broadcaster{conns: list.New()}
+}
+
+func (b *Broadcaster) addClient() (*client, *list.Element) {
+	c := &client{send: make(chan []byte, 8)}
+
+	b.mu.Lock()
+	e := b.conns.PushBack(c)
+	b.mu.Unlock()
+
+	return c, e
+}
+
+func (b *Broadcaster) removeClient(e *list.Element) {
+	b.mu.Lock()
+	b.conns.Remove(e)
+	b.mu.Unlock()
+}
+
+// Broadcast sends msg to every connected client. A client whose send
+// buffer is full is considered dead and is dropped.
+func (b *Broadcaster) Broadcast(msg Message) {
+	data, err := json.Marshal(msg)
+	if err != nil {
+		return
+	}
+
+	b.mu.Lock()
+	defer b.mu.Unlock()
+
+	var next *list.Element
+	for e := b.conns.Front(); e != nil; e = next {
+		next = e.Next()
+
+		c := e.Value.(*client)
+		select {
+		case c.send <- data:
+		default:
+			b.conns.Remove(e)
+			close(c.send)
+		}
+	}
+}
+
+func (b *Broadcaster) wsHandler() websocket.Handler {
+	return func(ws *websocket.Conn) {
+		c, e := b.addClient()
+		defer b.removeClient(e)
+
+		for data := range c.send {
+			if _, err := ws.Write(data); err != nil {
+				return
+			}
+		}
+	}
+}
+
+func (b *Broadcaster) sseHandler(w http.ResponseWriter, r *http.Request) {
+	flusher, ok := w.(http.Flusher)
+	if !ok {
+		http.Error(w, "streaming unsupported", http.StatusInternalServerError)
+		return
+	}
+
+	w.Header().Set("Content-Type", "text/event-stream")
+	w.Header().Set("Cache-Control", "no-cache")
+	w.Header().Set("Connection", "keep-alive")
+
+	c, e := b.addClient()
+	defer b.removeClient(e)
+
+	for {
+		select {
+		case data, ok := <-c.send:
+			if !ok {
+				return
+			}
+			fmt.Fprintf(w, "data: %s\n\n", data)
+			flusher.Flush()
+		case <-r.Context().Done():
+			return
+		}
+	}
+}
+
+const injectedJS = `(function() {
+	var proto = window.location.protocol === "https:" ? "wss:" : "ws:";
+	var socket = new WebSocket(proto + "//" + window.location.host + "/livereload/ws");
+	socket.onmessage = function(event) {
+		var msg = JSON.parse(event.data);
+		if (msg.type === "css") {
+			var links = document.getElementsByTagName("link");
+			for (var i = 0; i < links.length; i++) {
+				var link = links[i];
+				if (link.rel === "stylesheet") {
+					link.href = link.href.replace(/(\?|$)/, "?" + Date.now());
+				}
+			}
+		} else {
+			window.location.reload();
+		}
+	};
+})();
+`
+
+// Handler returns an http.Handler exposing the WebSocket endpoint at
+// /livereload/ws, the SSE endpoint at /livereload/sse, and the injectable
+// client script at /livereload.js.
+func (b *Broadcaster) Handler() http.Handler {
+	mux := http.NewServeMux()
+	mux.Handle("/livereload/ws", b.wsHandler())
+	mux.HandleFunc("/livereload/sse", b.sseHandler)
+	mux.HandleFunc("/livereload.js", func(w http.ResponseWriter, r *http.Request) {
+		w.Header().Set("Content-Type", "application/javascript")
+		io.WriteString(w, injectedJS)
+	})
+	return mux
+}
@@ -0,0 +1,74 @@
+package watch
+
+import "github.com/fsnotify/fsnotify"
+
+// fsnotifyWatcher adapts github.com/fsnotify/fsnotify to the Watcher
+// interface.
+type fsnotifyWatcher struct {
+	w      *fsnotify.Watcher
+	events chan Event
+	errors chan error
+}
+
+func newFsnotifyWatcher() (Watcher, error) {
+	w, err := fsnotify.NewWatcher()
+	if err != nil {
+		return nil, err
+	}
+
+	fw := &fsnotifyWatcher{
+		w:      w,
+		events: make(chan Event),
+		errors: make(chan error),
+	}
+	go fw.loop()
+
+	return fw, nil
+}
+
+func (fw *fsnotifyWatcher) loop() {
+	defer close(fw.events)
+	defer close(fw.errors)
+
+	for {
+		select {
+		case ev, ok := <-fw.w.Events:
+			if !ok {
+				return
+			}
+			fw.events <- Event{Name: ev.Name, Op: convertOp(ev.Op)}
+
+		case err, ok := <-fw.w.Errors:
+			if !ok {
+				return
+			}
+			fw.errors <- err
+		}
+	}
+}
+
+func convertOp(op fsnotify.Op) Op {
+	var out Op
+	if op&fsnotify.Create != 0 {
+		out |= Create
+	}
+	if op&fsnotify.Write != 0 {
+		out |= Write
+	}
+	if op&fsnotify.Remove != 0 {
+		out |= Remove
+	}
+	if op&fsnotify.Rename != 0 {
+		out |= Rename
+	}
+	if op&fsnotify.Chmod != 0 {
+		out |= Chmod
+	}
+	return out
+}
+
+func (fw *fsnotifyWatcher) Add(path string) error    { return fw.w.Add(path) }
+func (fw *fsnotifyWatcher) Remove(path string) error { return fw.w.Remove(path) }
+func (fw *fsnotifyWatcher) Events() <-chan Event     { return fw.events }
+func (fw *fsnotifyWatcher) Errors() <-chan error     { return fw.errors }
+func (fw *fsnotifyWatcher) Close() error             { return fw.w.Close() }
@@ -0,0 +1,208 @@
+package watch
+
+import (
+	"io/ioutil"
+	"os"
+	"path/filepath"
+	"sync"
+	"time"
+)
+
+// pollingWatcher periodically os.Stats its tracked directories (listing
+// their direct children) and individually tracked files, and synthesizes
+// Create/Write/Remove events from the changes it observes. It's the
+// fallback for filesystems where native events aren't delivered reliably
+// (NFS, SMB, Docker bind mounts on macOS).
+//
+// Watching a directory the way the native backend does - and getting
+// events for the files inside it for free - requires listing that
+// directory on every poll, not just stat-ing the directory entry itself:
+// writing to an existing file doesn't change its parent directory's mtime.
+type pollingWatcher struct {
+	interval time.Duration
+
+	mu    sync.Mutex
+	dirs  map[string]map[string]os.FileInfo
+	files map[string]os.FileInfo
+
+	events chan Event
+	errors chan error
+	done   chan struct{}
+}
+
+func newPollingWatcher(interval time.Duration) Watcher {
+	pw := &pollingWatcher{
+		interval: interval,
+		dirs:     map[string]map[string]os.FileInfo{},
+		files:    map[string]os.FileInfo{},
+		events:   make(chan Event),
+		errors:   make(chan error),
+		done:     make(chan struct{}),
+	}
+	go pw.loop()
+	return pw
+}
+
+func readDir(dir string) (map[string]os.FileInfo, error) {
+	entries, err := ioutil.ReadDir(dir)
+	if err != nil {
+		return nil, err
+	}
+
+	children := make(map[string]os.FileInfo, len(entries))
+	for _, entry := range entries {
+		children[filepath.Join(dir, entry.Name())] = entry
+	}
+	return children, nil
+}
+
+func (pw *pollingWatcher) Add(path string) error {
+	info, err := os.Stat(path)
+	if err != nil {
+		return err
+	}
+
+	if !info.IsDir() {
+		pw.mu.Lock()
+		pw.files[path] = info
+		pw.mu.Unlock()
+		return nil
+	}
+
+	children, err := readDir(path)
+	if err != nil {
+		return err
+	}
+
+	pw.mu.Lock()
+	pw.dirs[path] = children
+	pw.mu.Unlock()
+
+	return nil
+}
+
+func (pw *pollingWatcher) Remove(path string) error {
+	pw.mu.Lock()
+	delete(pw.dirs, path)
+	delete(pw.files, path)
+	pw.mu.Unlock()
+	return nil
+}
+
+func (pw *pollingWatcher) Events() <-chan Event { return pw.events }
+func (pw *pollingWatcher) Errors() <-chan error { return pw.errors }
+
+func (pw *pollingWatcher) Close() error {
+	close(pw.done)
+	return nil
+}
+
+func (pw *pollingWatcher) loop() {
+	ticker := time.NewTicker(pw.interval)
+	defer ticker.Stop()
+
+	for {
+		select {
+		case <-pw.done:
+			return
+		case <-ticker.C:
+			pw.poll()
+		}
+	}
+}
+
+func (pw *pollingWatcher) poll() {
+	pw.mu.Lock()
+	dirs := make([]string, 0, len(pw.dirs))
+	for d := range pw.dirs {
+		dirs = append(dirs, d)
+	}
+	files := make([]string, 0, len(pw.files))
+	for f := range pw.files {
+		files = append(files, f)
+	}
+	pw.mu.Unlock()
+
+	for _, dir := range dirs {
+		pw.pollDir(dir)
+	}
+	for _, file := range files {
+		pw.pollFile(file)
+	}
+}
+
+// pollDir re-lists dir and compares it against the snapshot from the
+// previous poll, emitting Create/Remove for entries that appeared or
+// disappeared and Write for entries whose size or mtime changed.
+func (pw *pollingWatcher) pollDir(dir string) {
+	children, err := readDir(dir)
+	if err != nil {
+		pw.mu.Lock()
+		delete(pw.dirs, dir)
+		pw.mu.Unlock()
+		pw.emit(Event{Name: dir, Op: Remove})
+		return
+	}
+
+	pw.mu.Lock()
+	prev := pw.dirs[dir]
+	pw.dirs[dir] = children
+	pw.mu.Unlock()
+
+	for path, info := range children {
+		old, existed := prev[path]
+		if !existed {
+			pw.emit(Event{Name: path, Op: Create})
+			continue
+		}
+		if !info.ModTime().Equal(old.ModTime()) || info.Size() != old.Size() {
+			pw.emit(Event{Name: path, Op: Write})
+		}
+	}
+
+	for path := range prev {
+		if _, ok := children[path]; !ok {
+			pw.emit(Event{Name: path, Op: Remove})
+		}
+	}
+}
+
+func (pw *pollingWatcher) pollFile(path string) {
+	info, err := os.Stat(path)
+
+	pw.mu.Lock()
+	prev, existed := pw.files[path]
+	pw.mu.Unlock()
+
+	if err != nil {
+		if existed {
+			pw.mu.Lock()
+			delete(pw.files, path)
+			pw.mu.Unlock()
+			pw.emit(Event{Name: path, Op: Remove})
+		}
+		return
+	}
+
+	if !existed {
+		pw.mu.Lock()
+		pw.files[path] = info
+		pw.mu.Unlock()
+		pw.emit(Event{Name: path, Op: Create})
+		return
+	}
+
+	if !info.ModTime().Equal(prev.ModTime()) || info.Size() != prev.Size() {
+		pw.mu.Lock()
+		pw.files[path] = info
+		pw.mu.Unlock()
+		pw.emit(Event{Name: path, Op: Write})
+	}
+}
+
+func (pw *pollingWatcher) emit(ev Event) {
+	select {
+	case pw.events <- ev:
+	case <-pw.done:
+	}
+}
@@ -0,0 +1,101 @@
+package watch
+
+import (
+	"os"
+	"path/filepath"
+	"testing"
+	"time"
+)
+
+func writeFile(t *testing.T, path, content string) {
+	t.Helper()
+	if err := os.WriteFile(path, []byte(content), 0644); err != nil {
+		t.Fatalf("WriteFile(%s): %s", path, err)
+	}
+}
+
+func newTestPollingWatcher() *pollingWatcher {
+	return &pollingWatcher{
+		dirs:   map[string]map[string]os.FileInfo{},
+		files:  map[string]os.FileInfo{},
+		events: make(chan Event, 16),
+		errors: make(chan error, 16),
+		done:   make(chan struct{}),
+	}
+}
+
+func assertEvent(t *testing.T, events chan Event, want Event) {
+	t.Helper()
+	select {
+	case got := <-events:
+		if got != want {
+			t.Errorf("got event %+v, want %+v", got, want)
+		}
+	case <-time.After(time.Second):
+		t.Errorf("no event received, want %+v", want)
+	}
+}
+
+func assertNoEvent(t *testing.T, events chan Event) {
+	t.Helper()
+	select {
+	case got := <-events:
+		t.Errorf("unexpected event %+v", got)
+	default:
+	}
+}
+
+func TestPollDir(t *testing.T) {
+	dir := t.TempDir()
+	aPath := filepath.Join(dir, "a.txt")
+	bPath := filepath.Join(dir, "b.txt")
+	writeFile(t, aPath, "one")
+
+	pw := newTestPollingWatcher()
+	if err := pw.Add(dir); err != nil {
+		t.Fatalf("Add(%s): %s", dir, err)
+	}
+
+	// Add only establishes the baseline; a poll with nothing changed
+	// should not emit an event for the pre-existing file.
+	pw.pollDir(dir)
+	assertNoEvent(t, pw.events)
+
+	writeFile(t, bPath, "two")
+	pw.pollDir(dir)
+	assertEvent(t, pw.events, Event{Name: bPath, Op: Create})
+
+	writeFile(t, aPath, "one but longer now")
+	pw.pollDir(dir)
+	assertEvent(t, pw.events, Event{Name: aPath, Op: Write})
+
+	if err := os.Remove(bPath); err != nil {
+		t.Fatal(err)
+	}
+	pw.pollDir(dir)
+	assertEvent(t, pw.events, Event{Name: bPath, Op: Remove})
+}
+
+func TestPollFile(t *testing.T) {
+	dir := t.TempDir()
+	path := filepath.Join(dir, "a.txt")
+	writeFile(t, path, "one")
+
+	pw := newTestPollingWatcher()
+	if err := pw.Add(path); err != nil {
+		t.Fatalf("Add(%s): %s", path, err)
+	}
+
+	pw.pollFile(path)
+	assertNoEvent(t, pw.events)
+
+	writeFile(t, path, "one but longer now")
+	pw.pollFile(path)
+	assertEvent(t, pw.events, Event{Name: path, Op: Write})
+
+	if err := os.Remove(path); err != nil {
+		t.Fatal(err)
+	}
+	pw.pollFile(path)
+	assertEvent(t, pw.events, Event{Name: path, Op: Remove})
+}
@@ -0,0 +1,68 @@
+/*
+Package watch abstracts file-system watching behind a Watcher interface so
+reload can run on top of either the native github.com/fsnotify/fsnotify
+backend (inotify, kqueue, ReadDirectoryChangesW) or a polling fallback for
+filesystems where native events aren't delivered reliably, such as NFS/SMB
+shares or Docker bind mounts on macOS.
+*/
+package watch
+
+import (
+	"fmt"
+	"time"
+)
+
+// Op describes the kind of change an Event represents. A single Event can
+// report more than one Op.
+type Op uint32
+
+const (
+	Create Op = 1 << iota
+	Write
+	Remove
+	Rename
+	Chmod
+)
+
+// Event reports that Op happened to the file or directory at Name.
+type Event struct {
+	Name string
+	Op   Op
+}
+
+// Has reports whether the event includes op.
+func (e Event) Has(op Op) bool {
+	return e.Op&op != 0
+}
+
+// Watcher watches a set of files and directories for changes. Add and
+// Remove may be called while events are being read.
+type Watcher interface {
+	Add(path string) error
+	Remove(path string) error
+	Events() <-chan Event
+	Errors() <-chan error
+	Close() error
+}
+
+// New returns a Watcher for the given backend kind: "fsnotify" (the
+// default) or "poll". "fsnotify" transparently falls back to polling if
+// the native backend can't be created, e.g. because the platform doesn't
+// support it or the inotify instance limit was hit. pollInterval is only used
+// by the polling backend.
+func New(kind string, pollInterval time.Duration) (Watcher, error) {
+	switch kind {
+	case "", "fsnotify":
+		w, err := newFsnotifyWatcher()
+		if err != nil {
+			return newPollingWatcher(pollInterval), nil
+		}
+		return w, nil
+
+	case "poll":
+		return newPollingWatcher(pollInterval), nil
+
+	default:
+		return nil, fmt.Errorf("unknown watcher %q, want fsnotify or poll", kind)
+	}
+}
@@ -0,0 +1,191 @@
+package main
+
+import (
+	"context"
+	"fmt"
+	"os"
+	"os/exec"
+	"os/signal"
+	"path/filepath"
+	"regexp"
+	"strings"
+	"sync"
+	"syscall"
+
+	"github.com/tadvi/reload/internal/config"
+	"github.com/tadvi/reload/internal/watch"
+)
+
+// runConfigMode loads the reload.yaml at path and runs every declared
+// project until reload receives SIGINT/SIGTERM.
+func runConfigMode(path string) {
+	cfg, err := config.Load(path)
+	if err != nil {
+		logger.Fatal(err)
+	}
+
+	ctx, cancel := context.WithCancel(context.Background())
+
+	sigCh := make(chan os.Signal, 1)
+	signal.Notify(sigCh, syscall.SIGINT, syscall.SIGTERM)
+	go func() {
+		sig := <-sigCh
+		logger.Println("Received", sig, "- stopping all projects")
+		cancel()
+	}()
+
+	runProjects(ctx, cfg)
+}
+
+// runProjects starts one goroutine per configured project and blocks until
+// ctx is cancelled and every project has shut down.
+func runProjects(ctx context.Context, cfg *config.Config) {
+	var wg sync.WaitGroup
+
+	for name, proj := range cfg.Projects {
+		wg.Add(1)
+		go func(name string, proj config.Project) {
+			defer wg.Done()
+			if err := runProject(ctx, name, proj); err != nil {
+				logger.Printf("project %s: %s", name, err)
+			}
+		}(name, proj)
+	}
+
+	wg.Wait()
+}
+
+// runProject watches proj.Path and drives its own build pipeline until ctx
+// is cancelled. Each project owns its own watch.Watcher and runs
+// independently of the others.
+func runProject(ctx context.Context, name string, proj config.Project) error {
+	watcher, err := watch.New(*flag_watcher, *flag_pollInterval)
+	if err != nil {
+		return fmt.Errorf("can't create watcher: %s", err)
+	}
+	defer watcher.Close()
+
+	err = filepath.Walk(proj.Path, func(path string, info os.FileInfo, err error) error {
+		if err != nil {
+			return err
+		}
+		if !info.IsDir() {
+			return nil
+		}
+		if matchesIgnoredPath(proj.IgnoredPaths, path) {
+			return filepath.SkipDir
+		}
+		return watcher.Add(path)
+	})
+	if err != nil {
+		return fmt.Errorf("can't watch %s: %s", proj.Path, err)
+	}
+
+	pattern, err := extensionPattern(proj.Extensions)
+	if err != nil {
+		return err
+	}
+
+	runScripts(name, proj, "before")
+
+	var current *os.Process
+	for {
+		select {
+		case <-ctx.Done():
+			if current != nil {
+				stopProcess(current, parseSignal(*flag_killSignal), *flag_killTimeout)
+			}
+			runScripts(name, proj, "after")
+			return nil
+
+		case ev := <-watcher.Events():
+			if ev.Name == "" || matchesIgnoredPath(proj.IgnoredPaths, ev.Name) {
+				continue
+			}
+			if !pattern.MatchString(ev.Name) {
+				continue
+			}
+
+			if current != nil {
+				stopProcess(current, parseSignal(*flag_killSignal), *flag_killTimeout)
+				current = nil
+			}
+
+			current = runScripts(name, proj, "change")
+
+		case err := <-watcher.Errors():
+			logger.Printf("project %s: watcher.Errors(): %s", name, err)
+		}
+	}
+}
+
+// runScripts runs every script of the given type in proj, in order, and
+// returns the *os.Process of the "change" script, if any, so callers can
+// track and stop the long-running build/run process on the next change or
+// on shutdown. config.Load rejects projects with more than one "change"
+// script, so at most one process is ever returned here.
+func runScripts(name string, proj config.Project, typ string) *os.Process {
+	var last *os.Process
+
+	for _, s := range proj.Scripts {
+		if s.Type != typ {
+			continue
+		}
+
+		args := strings.Split(s.Command, " ")
+		cmd := exec.Command(args[0], append(args[1:], proj.Args...)...)
+		cmd.Dir = proj.Path
+		cmd.Stdout = os.Stdout
+		cmd.Stderr = os.Stderr
+		cmd.Env = os.Environ()
+		for k, v := range proj.Env {
+			cmd.Env = append(cmd.Env, k+"="+v)
+		}
+
+		if err := cmd.Start(); err != nil {
+			logger.Printf("project %s: could not start %q: %s", name, s.Command, err)
+			continue
+		}
+
+		if typ != "change" {
+			if err := cmd.Wait(); err != nil {
+				logger.Printf("project %s: %q exited: %s", name, s.Command, err)
+			}
+			continue
+		}
+
+		last = cmd.Process
+	}
+
+	return last
+}
+
+// matchesIgnoredPath reports whether path matches any of the project's
+// ignored_paths glob patterns.
+func matchesIgnoredPath(ignored []string, path string) bool {
+	base := filepath.Base(path)
+	for _, pattern := range ignored {
+		if match, err := filepath.Match(pattern, path); err == nil && match {
+			return true
+		}
+		if match, err := filepath.Match(pattern, base); err == nil && match {
+			return true
+		}
+	}
+	return false
+}
+
+// extensionPattern builds a regexp matching any of the given file
+// extensions (e.g. ".go", ".js") anchored to the end of the path.
+func extensionPattern(extensions []string) (*regexp.Regexp, error) {
+	if len(extensions) == 0 {
+		return regexp.Compile(`.+`)
+	}
+
+	escaped := make([]string, len(extensions))
+	for i, ext := range extensions {
+		escaped[i] = regexp.QuoteMeta(ext)
+	}
+
+	return regexp.Compile(fmt.Sprintf(`(%s)$`, strings.Join(escaped, "|")))
+}